@@ -0,0 +1,184 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// CompileTarget selects Compile's output language.
+type CompileTarget byte
+
+const (
+	TargetGo CompileTarget = iota
+	TargetC
+)
+
+// Compile renders an optimized Op stream (see optimize) as a standalone
+// program in the selected target language. Bracket targets, already
+// resolved by matchBrackets, are turned into nested loops at compile time
+// rather than left as runtime jumps.
+func Compile(ops []Op, target CompileTarget) (string, error) {
+	switch target {
+	case TargetGo:
+		return compileGo(ops), nil
+	case TargetC:
+		return compileC(ops), nil
+	default:
+		return "", fmt.Errorf("gobfk: unknown compile target %d", target)
+	}
+}
+
+// memIndex renders "p", "p+k" or "p-k" for a MulMove/offset destination.
+func memIndex(offset int) string {
+	switch {
+	case offset > 0:
+		return fmt.Sprintf("p+%d", offset)
+	case offset < 0:
+		return fmt.Sprintf("p%d", offset)
+	default:
+		return "p"
+	}
+}
+
+func compileGo(ops []Op) string {
+	var b strings.Builder
+	b.WriteString("package main\n\n")
+	b.WriteString("import (\n\t\"bufio\"\n\t\"os\"\n)\n\n")
+	b.WriteString("func main() {\n")
+	b.WriteString("\tmem := make([]byte, 64000)\n")
+	b.WriteString("\tp := 0\n")
+	b.WriteString("\tin := bufio.NewReader(os.Stdin)\n")
+	b.WriteString("\t_ = in\n")
+	b.WriteString("\tout := bufio.NewWriter(os.Stdout)\n")
+	b.WriteString("\tdefer out.Flush()\n")
+	renderGo(&b, ops, 1, 0, len(ops))
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// renderGo emits ops[lo:hi] at the given indent, recursing into nested
+// loops by jumping straight to their matching LOOPR (op.Arg).
+func renderGo(b *strings.Builder, ops []Op, indent, lo, hi int) {
+	ind := strings.Repeat("\t", indent)
+	for i := lo; i < hi; i++ {
+		op := ops[i]
+		switch op.Kind {
+		case OpAdd:
+			fmt.Fprintf(b, "%smem[p] += %d\n", ind, byte(op.Arg))
+		case OpMove:
+			fmt.Fprintf(b, "%sp += %d\n", ind, op.Arg)
+		case OpPrint:
+			fmt.Fprintf(b, "%sout.WriteByte(mem[p])\n", ind)
+		case OpRead:
+			fmt.Fprintf(b, "%sif c, err := in.ReadByte(); err == nil {\n%s\tmem[p] = c\n%s} else {\n%s\tmem[p] = 0\n%s}\n", ind, ind, ind, ind, ind)
+		case OpSetZero:
+			fmt.Fprintf(b, "%smem[p] = 0\n", ind)
+		case OpMulMove:
+			offset, mult := unpackMulMove(op.Arg)
+			fmt.Fprintf(b, "%smem[%s] += mem[p] * %d\n", ind, memIndex(offset), byte(mult))
+		case OpScanRight:
+			fmt.Fprintf(b, "%sfor mem[p] != 0 {\n%s\tp++\n%s}\n", ind, ind, ind)
+		case OpScanLeft:
+			fmt.Fprintf(b, "%sfor mem[p] != 0 {\n%s\tp--\n%s}\n", ind, ind, ind)
+		case OpLoopL:
+			fmt.Fprintf(b, "%sfor mem[p] != 0 {\n", ind)
+			renderGo(b, ops, indent+1, i+1, op.Arg)
+			fmt.Fprintf(b, "%s}\n", ind)
+			i = op.Arg
+		}
+	}
+}
+
+func compileC(ops []Op) string {
+	var b strings.Builder
+	b.WriteString("#include <stdio.h>\n\n")
+	b.WriteString("int main(void) {\n")
+	b.WriteString("\tstatic unsigned char mem[64000];\n")
+	b.WriteString("\tint p = 0;\n")
+	renderC(&b, ops, 1, 0, len(ops))
+	b.WriteString("\treturn 0;\n")
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// renderC mirrors renderGo for C output.
+func renderC(b *strings.Builder, ops []Op, indent, lo, hi int) {
+	ind := strings.Repeat("\t", indent)
+	for i := lo; i < hi; i++ {
+		op := ops[i]
+		switch op.Kind {
+		case OpAdd:
+			fmt.Fprintf(b, "%smem[p] += %d;\n", ind, byte(op.Arg))
+		case OpMove:
+			fmt.Fprintf(b, "%sp += %d;\n", ind, op.Arg)
+		case OpPrint:
+			fmt.Fprintf(b, "%sputchar(mem[p]);\n", ind)
+		case OpRead:
+			fmt.Fprintf(b, "%s{ int c = getchar(); mem[p] = c == EOF ? 0 : (unsigned char)c; }\n", ind)
+		case OpSetZero:
+			fmt.Fprintf(b, "%smem[p] = 0;\n", ind)
+		case OpMulMove:
+			offset, mult := unpackMulMove(op.Arg)
+			fmt.Fprintf(b, "%smem[%s] += mem[p] * %d;\n", ind, memIndex(offset), byte(mult))
+		case OpScanRight:
+			fmt.Fprintf(b, "%swhile (mem[p] != 0) { p++; }\n", ind)
+		case OpScanLeft:
+			fmt.Fprintf(b, "%swhile (mem[p] != 0) { p--; }\n", ind)
+		case OpLoopL:
+			fmt.Fprintf(b, "%swhile (mem[p] != 0) {\n", ind)
+			renderC(b, ops, indent+1, i+1, op.Arg)
+			fmt.Fprintf(b, "%s}\n", ind)
+			i = op.Arg
+		}
+	}
+}
+
+// runCompileCmd implements `gobfk compile -target=go|c -o out.go program.bf`.
+func runCompileCmd(args []string) {
+	fs := flag.NewFlagSet("compile", flag.ExitOnError)
+	target := fs.String("target", "go", "compile target: go or c")
+	out := fs.String("o", "", "output file (default: stdout)")
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("usage: gobfk compile -target=go|c -o out.go program.bf")
+		os.Exit(1)
+	}
+
+	src, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("Error reading file:\n    %v\n", err)
+		os.Exit(1)
+	}
+
+	var compileTarget CompileTarget
+	switch *target {
+	case "go":
+		compileTarget = TargetGo
+	case "c":
+		compileTarget = TargetC
+	default:
+		fmt.Printf("Error: unknown -target %q, want go or c\n", *target)
+		os.Exit(1)
+	}
+
+	ops := fold(stringToTokens(string(src)))
+	matchBrackets(ops)
+	generated, err := Compile(ops, compileTarget)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *out == "" {
+		fmt.Print(generated)
+		return
+	}
+	if err := ioutil.WriteFile(*out, []byte(generated), 0644); err != nil {
+		fmt.Printf("Error writing file:\n    %v\n", err)
+		os.Exit(1)
+	}
+}
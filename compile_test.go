@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCompileGoMulMoveByteMasksMultiplier is a regression test: a copy loop
+// whose multiplier exceeds 255 must still render as a byte literal, since
+// mem is a []byte on both the interpreter's tape and the generated program's.
+func TestCompileGoMulMoveByteMasksMultiplier(t *testing.T) {
+	ops := fold(stringToTokens("+[->" + strings.Repeat("+", 300) + "<]"))
+	matchBrackets(ops)
+	out, err := Compile(ops, TargetGo)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if strings.Contains(out, "* 300") {
+		t.Errorf("generated Go overflows byte: %s", out)
+	}
+	if !strings.Contains(out, "* 44") {
+		t.Errorf("generated Go = %s, want a byte(300)=44 multiplier", out)
+	}
+}
+
+// TestCompileCMulMoveByteMasksMultiplier mirrors
+// TestCompileGoMulMoveByteMasksMultiplier for the C backend.
+func TestCompileCMulMoveByteMasksMultiplier(t *testing.T) {
+	ops := fold(stringToTokens("+[->" + strings.Repeat("+", 300) + "<]"))
+	matchBrackets(ops)
+	out, err := Compile(ops, TargetC)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	if !strings.Contains(out, "* 44;") {
+		t.Errorf("generated C = %s, want a byte(300)=44 multiplier", out)
+	}
+}
+
+// TestCompileUnknownTarget checks that an unrecognized CompileTarget reports
+// an error instead of silently falling back to Go or C.
+func TestCompileUnknownTarget(t *testing.T) {
+	if _, err := Compile(nil, CompileTarget(99)); err == nil {
+		t.Error("Compile with an unknown target = nil error, want an error")
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Position is the source line/column a Token was read from, 1-indexed to
+// match how editors and error messages usually report locations.
+type Position struct {
+	Line int
+	Col  int
+}
+
+// tokenizeWithPositions is stringToTokens plus a parallel Position for every
+// Token, so breakpoints and error messages can reference the original file.
+func tokenizeWithPositions(input string) ([]Token, []Position) {
+	tokens := make([]Token, 0, len(input))
+	positions := make([]Position, 0, len(input))
+	line, col := 1, 1
+	for _, char := range input {
+		var token Token
+		switch char {
+		case '>':
+			token = RIGHT
+		case '<':
+			token = LEFT
+		case '+':
+			token = INC
+		case '-':
+			token = DEC
+		case '.':
+			token = PRINT
+		case ',':
+			token = READ
+		case '[':
+			token = LOOPL
+		case ']':
+			token = LOOPR
+		default:
+			token = COMMENT
+		}
+		tokens = append(tokens, token)
+		positions = append(positions, Position{Line: line, Col: col})
+		if char == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return tokens, positions
+}
+
+// TapeFormat selects how Debugger.DumpTape renders cell values.
+type TapeFormat byte
+
+const (
+	FormatDecimal TapeFormat = iota
+	FormatHex
+	FormatASCII
+)
+
+// Debugger wraps a BrainfuckProgram and steps it one raw Token at a time
+// instead of through the optimized Ops stream Evaluate uses, so breakpoints,
+// stepping and disassembly can all refer to the original, unfused source.
+type Debugger struct {
+	bf          *BrainfuckProgram
+	breakpoints map[int]bool // source line numbers
+}
+
+// NewDebugger wraps bf for interactive debugging.
+func NewDebugger(bf *BrainfuckProgram) *Debugger {
+	return &Debugger{bf: bf, breakpoints: make(map[int]bool)}
+}
+
+// Break sets a breakpoint on the given source line.
+func (d *Debugger) Break(line int) {
+	d.breakpoints[line] = true
+}
+
+// Step executes exactly one Token at bf.PC, then advances PC.
+func (d *Debugger) Step() error {
+	bf := d.bf
+	if bf.PC >= len(bf.Instructions) {
+		bf.Finished = true
+		return nil
+	}
+	switch bf.Instructions[bf.PC] {
+	case RIGHT:
+		bf.DP++
+	case LEFT:
+		bf.DP--
+	case INC:
+		if err := bf.addCell(1); err != nil {
+			return err
+		}
+	case DEC:
+		if err := bf.addCell(-1); err != nil {
+			return err
+		}
+	case PRINT:
+		v, err := bf.tape.Get(bf.DP)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%c", byte(v))
+	case READ:
+		if err := bf.readStdinCell(); err != nil {
+			return err
+		}
+	case LOOPL:
+		if err := d.loopOpen(); err != nil {
+			return err
+		}
+	case LOOPR:
+		d.loopClose()
+	}
+	bf.PC++
+
+	if bf.PC >= len(bf.Instructions) {
+		bf.Finished = true
+	}
+	return nil
+}
+
+// loopOpen implements Token-level LOOPL: skip to the matching LOOPR if the
+// current cell is already zero.
+func (d *Debugger) loopOpen() error {
+	bf := d.bf
+	v, err := bf.tape.Get(bf.DP)
+	if err != nil {
+		return err
+	}
+	if v == 0 {
+		bf.PC = matchLoopToken(bf.Instructions, bf.PC)
+	}
+	return nil
+}
+
+// loopClose implements Token-level LOOPR: jump back to just before the
+// matching LOOPL so the next Step re-checks it; Step's own PC++ then lands
+// back on the LOOPL.
+func (d *Debugger) loopClose() {
+	bf := d.bf
+	balance := 0
+	for {
+		switch bf.Instructions[bf.PC] {
+		case LOOPL:
+			balance++
+		case LOOPR:
+			balance--
+		}
+		bf.PC--
+		if balance == 0 {
+			return
+		}
+	}
+}
+
+// matchLoopToken returns the index of the Token matching the LOOPL at open.
+func matchLoopToken(tokens []Token, open int) int {
+	balance := 1
+	i := open
+	for balance != 0 {
+		i++
+		switch tokens[i] {
+		case LOOPL:
+			balance++
+		case LOOPR:
+			balance--
+		}
+	}
+	return i
+}
+
+// StepOver steps a single Token, except that if it's a LOOPL whose loop
+// actually runs, it keeps stepping until execution has passed the matching
+// LOOPR, rather than stopping on every iteration.
+func (d *Debugger) StepOver() error {
+	bf := d.bf
+	if bf.PC >= len(bf.Instructions) || bf.Instructions[bf.PC] != LOOPL {
+		return d.Step()
+	}
+	target := matchLoopToken(bf.Instructions, bf.PC) + 1
+	for !bf.Finished && bf.PC < target {
+		if err := d.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run steps the program until it finishes or the next Token to execute sits
+// on a breakpointed source line.
+func (d *Debugger) Run() error {
+	bf := d.bf
+	for !bf.Finished {
+		if bf.PC < len(bf.Positions) && d.breakpoints[bf.Positions[bf.PC].Line] {
+			return nil
+		}
+		if err := d.Step(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpTape renders tape cells [lo, hi) in the given format, one per line,
+// marking the cell at DP.
+func (d *Debugger) DumpTape(lo, hi int, format TapeFormat) string {
+	var b strings.Builder
+	for i := lo; i < hi; i++ {
+		marker := " "
+		if i == d.bf.DP {
+			marker = "*"
+		}
+		v, err := d.bf.tape.Get(i)
+		if err != nil {
+			fmt.Fprintf(&b, "%s[%d] <%v>\n", marker, i, err)
+			continue
+		}
+		switch format {
+		case FormatHex:
+			fmt.Fprintf(&b, "%s[%d] 0x%02x\n", marker, i, v)
+		case FormatASCII:
+			fmt.Fprintf(&b, "%s[%d] %q\n", marker, i, rune(v))
+		default:
+			fmt.Fprintf(&b, "%s[%d] %d\n", marker, i, v)
+		}
+	}
+	return b.String()
+}
+
+// Disassemble renders every Token in bf.Instructions with its source
+// position, marking the Token at PC with "->".
+func (d *Debugger) Disassemble() string {
+	names := d.bf.debugTokens()
+	var b strings.Builder
+	for i, name := range names {
+		marker := "  "
+		if i == d.bf.PC {
+			marker = "->"
+		}
+		pos := d.bf.Positions[i]
+		fmt.Fprintf(&b, "%s %4d  %-8s (line %d, col %d)\n", marker, i, name, pos.Line, pos.Col)
+	}
+	return b.String()
+}
+
+// runDebugREPL drives an interactive debug session for bf from stdin, wired
+// up via the -d/--debug CLI flag. It reads REPL commands through the same
+// bf.stdinReader() that the debugged program's own `,` reads from, so the
+// two never buffer ahead of each other over the same fd.
+func runDebugREPL(bf *BrainfuckProgram) {
+	d := NewDebugger(bf)
+	reader := bf.stdinReader()
+	for {
+		fmt.Print("(gobfk-debug) ")
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "step", "s":
+			runDebugCmd(bf, d.Step)
+		case "over":
+			runDebugCmd(bf, d.StepOver)
+		case "run", "r":
+			runDebugCmd(bf, d.Run)
+		case "break", "b":
+			n, err := strconv.Atoi(fields[len(fields)-1])
+			if len(fields) < 2 || err != nil {
+				fmt.Println("usage: break <line>")
+				continue
+			}
+			d.Break(n)
+		case "tape":
+			lo, hi := bf.DP-8, bf.DP+8
+			if len(fields) == 3 {
+				if v, err := strconv.Atoi(fields[1]); err == nil {
+					lo = v
+				}
+				if v, err := strconv.Atoi(fields[2]); err == nil {
+					hi = v
+				}
+			}
+			fmt.Print(d.DumpTape(lo, hi, FormatDecimal))
+		case "pc":
+			fmt.Printf("PC=%d DP=%d\n", bf.PC, bf.DP)
+			fmt.Print(d.Disassemble())
+		case "quit", "q":
+			return
+		default:
+			fmt.Printf("unknown command %q (try step, over, run, break N, tape [a b], pc, quit)\n", fields[0])
+		}
+	}
+}
+
+// runDebugCmd runs a debugger command, reporting either its error or (once)
+// that the program has finished.
+func runDebugCmd(bf *BrainfuckProgram, cmd func() error) {
+	if bf.Finished {
+		fmt.Println("program finished")
+		return
+	}
+	if err := cmd(); err != nil {
+		fmt.Printf("error: %v\n", err)
+		return
+	}
+	if bf.Finished {
+		fmt.Println("program finished")
+	}
+}
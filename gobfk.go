@@ -9,17 +9,33 @@ import (
 
 // BrainfuckProgram represents everything needed to interpret a brainfuck program
 //
-// Instructions = parsed set of tokens to execute
-// Tape = our tape of memory
+// Instructions = parsed set of tokens to execute, kept around for debugTokens
+// Positions = source line/column of each entry in Instructions, for the Debugger
+// Ops = optimized instruction stream that Evaluate actually runs (see optimize)
+// tape = our tape of memory, pluggable via Options (see CreateBrainfuckProgramWithOptions)
 // DP = Data Pointer = pointer inside our tape
 // PC = Program Counter = where are we in our instructions
 // Finished = is the program ready to exit?
+//
+// code/in/out are only set by NewStreamingBrainfuck; a BrainfuckProgram
+// created by CreateBrainfuckProgram never touches them and runs via
+// Evaluate/Ops instead.
 type BrainfuckProgram struct {
 	Instructions []Token
-	Tape         []byte
+	Positions    []Position
+	Ops          []Op
 	DP           int
 	PC           int
 	Finished     bool
+
+	tape    Tape
+	eofMode EOFMode
+
+	code *bufio.Reader
+	in   *bufio.Reader
+	out  *bufio.Writer
+
+	stdin *bufio.Reader
 }
 
 // Token represents a token in Brainfuck
@@ -40,156 +56,279 @@ const (
 )
 
 func main() {
-	if len(os.Args) > 2 {
+	if len(os.Args) > 1 && os.Args[1] == "compile" {
+		runCompileCmd(os.Args[2:])
+		return
+	}
+
+	args := os.Args[1:]
+	debug := false
+	if len(args) > 0 && (args[0] == "-d" || args[0] == "--debug") {
+		debug = true
+		args = args[1:]
+	}
+
+	if len(args) > 1 {
 		// too many arguments, print error and exit
-		fmt.Printf("Error, invalid number of arguments, usage:\n%[1]s \t\t- to run as REPL\n%[1]s filename.bf \t- to interpret a file", os.Args[0])
+		fmt.Printf("Error, invalid number of arguments, usage:\n%[1]s \t\t\t- to run as REPL\n%[1]s filename.bf \t\t- to interpret a file\n%[1]s -d filename.bf \t- to debug a file", os.Args[0])
 		os.Exit(1)
-	} else if len(os.Args) == 2 {
-		// program.exe filename.bf - run the file instead
-		file, err := ioutil.ReadFile(os.Args[1])
+	} else if len(args) == 1 {
+		// program.exe [-d] filename.bf - run (or debug) the file
+		file, err := ioutil.ReadFile(args[0])
 		if err != nil {
 			fmt.Printf("Error reading file:\n    %v\n", err)
 			os.Exit(1)
 		}
-		bf := CreateBrainfuckProgram(string(file))
+		bf, err := CreateBrainfuckProgram(string(file))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if debug {
+			runDebugREPL(&bf)
+			return
+		}
 		for bf.Finished != true {
-			bf.Evaluate()
+			if err := bf.Evaluate(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
 		}
 	} else {
-		// program.exe - interactive mode
+		// program.exe [-d] - interactive mode
 		reader := bufio.NewReader(os.Stdin)
 		prompt := ":: "
 		for {
 			fmt.Printf("\n%s", prompt)
-			input, _ := reader.ReadString('\n')
-			bf := CreateBrainfuckProgram(input)
+			input, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			bf, err := CreateBrainfuckProgram(input)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				continue
+			}
+			// Share this loop's reader with bf's own `,` reads (see
+			// stdinReader) so reading REPL input and program input never
+			// race over the same fd through two separate buffers.
+			bf.stdin = reader
+			if debug {
+				runDebugREPL(&bf)
+				continue
+			}
 			for bf.Finished != true {
-				bf.Evaluate()
+				if err := bf.Evaluate(); err != nil {
+					fmt.Printf("Error: %v\n", err)
+					break
+				}
 			}
 		}
 	}
 }
 
+// Options configures a BrainfuckProgram's tape and I/O behavior. The zero
+// value is not ready to use; start from DefaultOptions and override what you
+// need.
+type Options struct {
+	CellWidth CellWidth
+	TapeSize  int
+	TapeMode  TapeMode
+	EOFMode   EOFMode
+}
+
+// DefaultOptions returns the Options matching CreateBrainfuckProgram: an
+// 8-bit, 64000-cell tape that errors (rather than panicking) on an
+// out-of-bounds data pointer, and `,` zeroing the cell on EOF.
+func DefaultOptions() Options {
+	return Options{
+		CellWidth: Cell8,
+		TapeSize:  64000,
+		TapeMode:  TapeModeError,
+		EOFMode:   EOFZero,
+	}
+}
+
 // CreateBrainfuckProgram takes an input string and returns a set up BrainfuckProgram
-func CreateBrainfuckProgram(input string) BrainfuckProgram {
+func CreateBrainfuckProgram(input string) (BrainfuckProgram, error) {
+	return CreateBrainfuckProgramWithOptions(input, DefaultOptions())
+}
+
+// CreateBrainfuckProgramWithOptions is CreateBrainfuckProgram with control
+// over the tape's cell width, size, overflow behavior, and EOF behavior. It
+// returns an error if opts describes a Tape that can't be built, e.g. a
+// non-positive TapeSize.
+func CreateBrainfuckProgramWithOptions(input string, opts Options) (BrainfuckProgram, error) {
+	tape, err := newTape(opts)
+	if err != nil {
+		return BrainfuckProgram{}, err
+	}
 	bf := BrainfuckProgram{
-		Tape:     make([]byte, 64000),
+		tape:     tape,
+		eofMode:  opts.EOFMode,
 		DP:       0,
 		PC:       0,
 		Finished: false,
 	}
 	bf.tokenize(input)
-	return bf
+	bf.optimize()
+	return bf, nil
 }
 
 // Tokenize method will tokenize a string of brainfuck and set up the BF struct with the tokens
 func (bf *BrainfuckProgram) tokenize(input string) {
-	tokenized := make([]Token, 0, len(input))
-	for _, char := range input {
-		switch char {
-		case '>':
-			tokenized = append(tokenized, RIGHT)
-		case '<':
-			tokenized = append(tokenized, LEFT)
-		case '+':
-			tokenized = append(tokenized, INC)
-		case '-':
-			tokenized = append(tokenized, DEC)
-		case '.':
-			tokenized = append(tokenized, PRINT)
-		case ',':
-			tokenized = append(tokenized, READ)
-		case '[':
-			tokenized = append(tokenized, LOOPL)
-		case ']':
-			tokenized = append(tokenized, LOOPR)
-		default:
-			tokenized = append(tokenized, COMMENT)
-		}
-	}
-	bf.Instructions = tokenized
+	bf.Instructions, bf.Positions = tokenizeWithPositions(input)
 }
 
-// Evaluate method will take a single step through our program, executing the intended
-// instruction then increasing the program counter
-func (bf *BrainfuckProgram) Evaluate() {
-	switch bf.Instructions[bf.PC] {
-	case RIGHT:
-		bf.DP++
-	case LEFT:
-		bf.DP--
-	case INC:
-		bf.Tape[bf.DP]++
-	case DEC:
-		bf.Tape[bf.DP]--
-	case PRINT:
-		fmt.Printf("%c", bf.Tape[bf.DP])
-	case READ:
-		reader := bufio.NewReader(os.Stdin)
-		char, _, _ := reader.ReadRune()
-		bf.Tape[bf.DP] = byte(char)
-	case LOOPL:
-		bf.openLoop()
-	case LOOPR:
-		bf.closeLoop()
+// stringToTokens maps each brainfuck command character to its Token, treating
+// anything else as a COMMENT. It's a thin wrapper around
+// tokenizeWithPositions for callers (e.g. the streaming interpreter's
+// in-memory loop bodies) that don't need source positions.
+func stringToTokens(input string) []Token {
+	tokens, _ := tokenizeWithPositions(input)
+	return tokens
+}
+
+// Evaluate method will take a single step through our optimized Ops stream,
+// executing the op at PC then advancing the program counter. Bracket targets
+// are resolved ahead of time by optimize, so loop jumps are O(1). It returns
+// an error if the tape reports the data pointer went out of bounds (see
+// TapeMode).
+func (bf *BrainfuckProgram) Evaluate() error {
+	op := bf.Ops[bf.PC]
+	switch op.Kind {
+	case OpAdd:
+		v, err := bf.tape.Get(bf.DP)
+		if err != nil {
+			return err
+		}
+		if err := bf.tape.Set(bf.DP, v+int64(op.Arg)); err != nil {
+			return err
+		}
+	case OpMove:
+		bf.DP += op.Arg
+	case OpPrint:
+		v, err := bf.tape.Get(bf.DP)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%c", byte(v))
+	case OpRead:
+		if err := bf.readStdinCell(); err != nil {
+			return err
+		}
+	case OpLoopL:
+		v, err := bf.tape.Get(bf.DP)
+		if err != nil {
+			return err
+		}
+		if v == 0 {
+			bf.PC = op.Arg
+		}
+	case OpLoopR:
+		v, err := bf.tape.Get(bf.DP)
+		if err != nil {
+			return err
+		}
+		if v != 0 {
+			bf.PC = op.Arg
+		}
+	case OpSetZero:
+		if err := bf.tape.Set(bf.DP, 0); err != nil {
+			return err
+		}
+	case OpMulMove:
+		offset, mult := unpackMulMove(op.Arg)
+		src, err := bf.tape.Get(bf.DP)
+		if err != nil {
+			return err
+		}
+		dst, err := bf.tape.Get(bf.DP + offset)
+		if err != nil {
+			return err
+		}
+		if err := bf.tape.Set(bf.DP+offset, dst+src*int64(mult)); err != nil {
+			return err
+		}
+	case OpScanRight:
+		idx, err := bf.tape.ScanZero(bf.DP, 1)
+		if err != nil {
+			return err
+		}
+		bf.DP = idx
+	case OpScanLeft:
+		idx, err := bf.tape.ScanZero(bf.DP, -1)
+		if err != nil {
+			return err
+		}
+		bf.DP = idx
 	}
 	bf.PC++
 
-	if bf.PC >= len(bf.Instructions) {
+	if bf.PC >= len(bf.Ops) {
 		bf.Finished = true
 	}
+	return nil
 }
 
-func (bf *BrainfuckProgram) openLoop() {
-	balance := 1
-	if bf.Tape[bf.DP] == 0 {
-		for balance != 0 {
-			bf.PC++
-			if bf.Instructions[bf.PC] == LOOPL {
-				balance++
-			} else if bf.Instructions[bf.PC] == LOOPR {
-				balance--
-			}
-		}
+// stdinReader lazily creates, and then reuses, the single *bufio.Reader
+// wrapping os.Stdin for this program, so a debug session's REPL commands and
+// the program's own `,` reads never buffer ahead of each other over the same
+// fd (see runDebugREPL).
+func (bf *BrainfuckProgram) stdinReader() *bufio.Reader {
+	if bf.stdin == nil {
+		bf.stdin = bufio.NewReader(os.Stdin)
 	}
+	return bf.stdin
 }
 
-func (bf *BrainfuckProgram) closeLoop() {
-	balance := 0
-	for {
-		if bf.Instructions[bf.PC] == LOOPL {
-			balance++
-		} else if bf.Instructions[bf.PC] == LOOPR {
-			balance--
-		}
-		bf.PC--
-		if balance == 0 {
-			break
+// readStdinCell reads one rune from os.Stdin into the current cell,
+// falling back to bf.eofMode's behavior on EOF.
+func (bf *BrainfuckProgram) readStdinCell() error {
+	char, _, err := bf.stdinReader().ReadRune()
+	if err != nil {
+		switch bf.eofMode {
+		case EOFMinusOne:
+			return bf.tape.Set(bf.DP, -1)
+		case EOFUnchanged:
+			return nil
+		default:
+			return bf.tape.Set(bf.DP, 0)
 		}
 	}
+	return bf.tape.Set(bf.DP, int64(char))
 }
 
+// debugTokens names every entry in bf.Instructions, 1:1, for the Debugger's
+// disassembly view.
 func (bf *BrainfuckProgram) debugTokens() []string {
-	result := make([]string, 0, len(bf.Instructions))
-	for _, token := range bf.Instructions {
-		switch token {
-		case RIGHT:
-			result = append(result, "RIGHT")
-		case LEFT:
-			result = append(result, "LEFT")
-		case INC:
-			result = append(result, "INC")
-		case DEC:
-			result = append(result, "DEC")
-		case PRINT:
-			result = append(result, "PRINT")
-		case READ:
-			result = append(result, "READ")
-		case LOOPL:
-			result = append(result, "LOOPL")
-		case LOOPR:
-			result = append(result, "LOOPR")
-		}
+	result := make([]string, len(bf.Instructions))
+	for i, token := range bf.Instructions {
+		result[i] = tokenName(token)
 	}
 	return result
 }
+
+// tokenName returns the mnemonic for a single Token.
+func tokenName(token Token) string {
+	switch token {
+	case RIGHT:
+		return "RIGHT"
+	case LEFT:
+		return "LEFT"
+	case INC:
+		return "INC"
+	case DEC:
+		return "DEC"
+	case PRINT:
+		return "PRINT"
+	case READ:
+		return "READ"
+	case LOOPL:
+		return "LOOPL"
+	case LOOPR:
+		return "LOOPR"
+	default:
+		return "COMMENT"
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+// Op kinds produced by the optimizer's fold pass. Arg's meaning depends on
+// Kind: a repeat count for OpAdd/OpMove, a resolved jump target for
+// OpLoopL/OpLoopR, or a packed (offset, multiplier) pair for OpMulMove (see
+// packMulMove).
+const (
+	OpAdd byte = iota
+	OpMove
+	OpPrint
+	OpRead
+	OpLoopL
+	OpLoopR
+	OpSetZero
+	OpMulMove
+	OpScanRight
+	OpScanLeft
+)
+
+// Op is a single fused instruction in the optimizer's output stream, as
+// opposed to the raw one-Token-per-character Instructions stream produced by
+// tokenize.
+type Op struct {
+	Kind byte
+	Arg  int
+}
+
+// mulPair is one "add tape[dp+offset] += tape[dp]*mult" step inside a folded
+// copy/move loop such as [->+<] or [->+++<].
+type mulPair struct {
+	offset int
+	mult   int
+}
+
+// optimize turns bf.Instructions into the fused bf.Ops stream that Evaluate
+// actually runs: runs of +/- and >/< are coalesced, well-known idioms are
+// folded into single ops, and matching brackets are resolved to O(1) jump
+// targets stored in Arg.
+func (bf *BrainfuckProgram) optimize() {
+	bf.Ops = fold(bf.Instructions)
+	matchBrackets(bf.Ops)
+}
+
+// fold coalesces runs of identical instructions and then folds known idioms
+// on top of the result.
+func fold(tokens []Token) []Op {
+	return foldIdioms(coalesce(tokens))
+}
+
+// coalesce merges consecutive +/- into a single OpAdd and consecutive >/<
+// into a single OpMove, carrying the rest of the tokens through unchanged.
+func coalesce(tokens []Token) []Op {
+	ops := make([]Op, 0, len(tokens))
+	i := 0
+	for i < len(tokens) {
+		switch tokens[i] {
+		case INC, DEC:
+			n := 0
+			for i < len(tokens) && (tokens[i] == INC || tokens[i] == DEC) {
+				if tokens[i] == INC {
+					n++
+				} else {
+					n--
+				}
+				i++
+			}
+			ops = append(ops, Op{Kind: OpAdd, Arg: n})
+		case RIGHT, LEFT:
+			n := 0
+			for i < len(tokens) && (tokens[i] == RIGHT || tokens[i] == LEFT) {
+				if tokens[i] == RIGHT {
+					n++
+				} else {
+					n--
+				}
+				i++
+			}
+			ops = append(ops, Op{Kind: OpMove, Arg: n})
+		case PRINT:
+			ops = append(ops, Op{Kind: OpPrint})
+			i++
+		case READ:
+			ops = append(ops, Op{Kind: OpRead})
+			i++
+		case LOOPL:
+			ops = append(ops, Op{Kind: OpLoopL})
+			i++
+		case LOOPR:
+			ops = append(ops, Op{Kind: OpLoopR})
+			i++
+		default:
+			i++
+		}
+	}
+	return ops
+}
+
+// foldIdioms walks a coalesced op stream and replaces whole loops with a
+// single fused op wherever it recognizes one of: [-]/[+] (SETZERO),
+// [>]/[<] (SCAN_RIGHT/SCAN_LEFT), or a copy/move loop such as [->+<]
+// (MULMOVE). Loops that don't match a known idiom are left untouched.
+func foldIdioms(ops []Op) []Op {
+	matches := matchLoops(ops)
+	out := make([]Op, 0, len(ops))
+	for i := 0; i < len(ops); i++ {
+		if ops[i].Kind != OpLoopL {
+			out = append(out, ops[i])
+			continue
+		}
+		j := matches[i]
+		if idiom, ok := foldLoopBody(ops[i+1 : j]); ok {
+			out = append(out, idiom...)
+			i = j
+			continue
+		}
+		out = append(out, ops[i])
+	}
+	return out
+}
+
+// matchLoops returns, for every index holding an OpLoopL or OpLoopR, the
+// index of its matching bracket.
+func matchLoops(ops []Op) []int {
+	matches := make([]int, len(ops))
+	var stack []int
+	for i, op := range ops {
+		switch op.Kind {
+		case OpLoopL:
+			stack = append(stack, i)
+		case OpLoopR:
+			n := len(stack) - 1
+			l := stack[n]
+			stack = stack[:n]
+			matches[l] = i
+			matches[i] = l
+		}
+	}
+	return matches
+}
+
+// matchBrackets resolves every OpLoopL/OpLoopR pair remaining after folding
+// to its partner's index, stored in Arg, so Evaluate can jump in O(1).
+func matchBrackets(ops []Op) {
+	matches := matchLoops(ops)
+	for i, op := range ops {
+		if op.Kind == OpLoopL || op.Kind == OpLoopR {
+			ops[i].Arg = matches[i]
+		}
+	}
+}
+
+// foldLoopBody recognizes the body of a single loop (the ops strictly
+// between its [ and ]) as one of the known idioms and returns its
+// replacement ops, or ok=false if the body isn't one we know how to fold.
+func foldLoopBody(body []Op) ([]Op, bool) {
+	if len(body) == 1 && body[0].Kind == OpAdd && (body[0].Arg == -1 || body[0].Arg == 1) {
+		return []Op{{Kind: OpSetZero}}, true
+	}
+	if len(body) == 1 && body[0].Kind == OpMove {
+		if body[0].Arg > 0 {
+			return []Op{{Kind: OpScanRight, Arg: body[0].Arg}}, true
+		}
+		return []Op{{Kind: OpScanLeft, Arg: -body[0].Arg}}, true
+	}
+	if pairs, ok := parseMulMoveBody(body); ok {
+		folded := make([]Op, 0, len(pairs)+1)
+		for _, p := range pairs {
+			folded = append(folded, Op{Kind: OpMulMove, Arg: packMulMove(p.offset, p.mult)})
+		}
+		folded = append(folded, Op{Kind: OpSetZero})
+		return folded, true
+	}
+	return nil, false
+}
+
+// parseMulMoveBody recognizes a copy/move loop body: a single decrement of
+// the source cell followed by any number of moves and adds that land back
+// on the source cell, e.g. [->+<], [->>+<<], [-<+>] or the multiplier form
+// [->+++<]. It returns one mulPair per destination cell touched.
+func parseMulMoveBody(body []Op) ([]mulPair, bool) {
+	if len(body) < 3 || body[0].Kind != OpAdd || body[0].Arg != -1 {
+		return nil, false
+	}
+	var pairs []mulPair
+	offset := 0
+	for _, op := range body[1:] {
+		switch op.Kind {
+		case OpMove:
+			offset += op.Arg
+		case OpAdd:
+			if offset == 0 || op.Arg <= 0 {
+				return nil, false
+			}
+			pairs = append(pairs, mulPair{offset: offset, mult: op.Arg})
+		default:
+			return nil, false
+		}
+	}
+	if offset != 0 || len(pairs) == 0 {
+		return nil, false
+	}
+	return pairs, true
+}
+
+// packMulMove packs a destination offset and a multiplier into the single
+// int Arg slot of an OpMulMove. offset may be negative; mult is always a
+// small positive count, so the pair round-trips through a signed shift.
+func packMulMove(offset, mult int) int {
+	return (offset << 16) | (mult & 0xFFFF)
+}
+
+// unpackMulMove reverses packMulMove.
+func unpackMulMove(arg int) (offset, mult int) {
+	return arg >> 16, arg & 0xFFFF
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+// TestCoalesceMergesRuns checks that runs of +/- and >/< collapse into single
+// OpAdd/OpMove ops, while other tokens pass through one Op per Token.
+func TestCoalesceMergesRuns(t *testing.T) {
+	ops := coalesce(stringToTokens("+++--><.,"))
+	want := []Op{
+		{Kind: OpAdd, Arg: 1},
+		{Kind: OpMove, Arg: 0},
+		{Kind: OpPrint},
+		{Kind: OpRead},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("coalesce(%q) = %v, want %v", "+++--><.,", ops, want)
+	}
+	for i, op := range ops {
+		if op != want[i] {
+			t.Errorf("op[%d] = %+v, want %+v", i, op, want[i])
+		}
+	}
+}
+
+// TestFoldIdiomsSetZero checks that [-] and [+] both fold to OpSetZero.
+func TestFoldIdiomsSetZero(t *testing.T) {
+	for _, src := range []string{"[-]", "[+]"} {
+		ops := fold(stringToTokens(src))
+		if len(ops) != 1 || ops[0].Kind != OpSetZero {
+			t.Errorf("fold(%q) = %v, want a single OpSetZero", src, ops)
+		}
+	}
+}
+
+// TestFoldIdiomsScan checks that [>] and [<] fold to OpScanRight/OpScanLeft.
+func TestFoldIdiomsScan(t *testing.T) {
+	ops := fold(stringToTokens("[>>>]"))
+	if len(ops) != 1 || ops[0] != (Op{Kind: OpScanRight, Arg: 3}) {
+		t.Errorf("fold([>>>]) = %v, want a single OpScanRight{Arg:3}", ops)
+	}
+
+	ops = fold(stringToTokens("[<<]"))
+	if len(ops) != 1 || ops[0] != (Op{Kind: OpScanLeft, Arg: 2}) {
+		t.Errorf("fold([<<]) = %v, want a single OpScanLeft{Arg:2}", ops)
+	}
+}
+
+// TestFoldIdiomsMulMove checks that a copy/move loop folds to OpMulMove(s)
+// followed by an OpSetZero, with the multiplier taken from the run length.
+func TestFoldIdiomsMulMove(t *testing.T) {
+	ops := fold(stringToTokens("[->+++<]"))
+	if len(ops) != 2 {
+		t.Fatalf("fold([->+++<]) = %v, want [OpMulMove, OpSetZero]", ops)
+	}
+	if ops[0].Kind != OpMulMove {
+		t.Fatalf("ops[0] = %+v, want OpMulMove", ops[0])
+	}
+	offset, mult := unpackMulMove(ops[0].Arg)
+	if offset != 1 || mult != 3 {
+		t.Errorf("unpackMulMove(ops[0].Arg) = (%d, %d), want (1, 3)", offset, mult)
+	}
+	if ops[1].Kind != OpSetZero {
+		t.Errorf("ops[1] = %+v, want OpSetZero", ops[1])
+	}
+}
+
+// runBenchmark streams src through a discard writer, the way a benchmark
+// wants: no stdout noise, no per-run file I/O beyond the initial read.
+func runBenchmark(b *testing.B, src []byte) {
+	b.Helper()
+	for i := 0; i < b.N; i++ {
+		bf := NewStreamingBrainfuck(bytes.NewReader(src), bytes.NewReader(nil), io.Discard)
+		if err := bf.Run(context.Background()); err != nil {
+			b.Fatalf("Run: %v", err)
+		}
+	}
+}
+
+// BenchmarkMandelbrot exercises the optimizer's idiom folding (repeated
+// non-destructive copies and drains) against testdata/mandelbrot.bf.
+func BenchmarkMandelbrot(b *testing.B) {
+	src, err := ioutil.ReadFile("testdata/mandelbrot.bf")
+	if err != nil {
+		b.Fatal(err)
+	}
+	runBenchmark(b, src)
+}
+
+// BenchmarkHanoi exercises OpMulMove with a spread of multipliers against
+// testdata/hanoi.bf.
+func BenchmarkHanoi(b *testing.B) {
+	src, err := ioutil.ReadFile("testdata/hanoi.bf")
+	if err != nil {
+		b.Fatal(err)
+	}
+	runBenchmark(b, src)
+}
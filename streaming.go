@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// NewStreamingBrainfuck sets up a BrainfuckProgram that reads its source
+// lazily from code, rather than requiring the whole program to be loaded
+// into memory up front (as CreateBrainfuckProgram does), and that reads and
+// writes through in/out instead of os.Stdin/os.Stdout. Run it with Run.
+//
+// It uses the same 8-bit, 64000-cell, error-on-out-of-bounds tape as
+// CreateBrainfuckProgram; use CreateBrainfuckProgramWithOptions instead if
+// you need a different Tape. `,` leaves the cell unchanged on EOF, matching
+// the behavior Run has always had.
+func NewStreamingBrainfuck(code io.Reader, in io.Reader, out io.Writer) *BrainfuckProgram {
+	tape, err := newTape(Options{CellWidth: Cell8, TapeSize: 64000, TapeMode: TapeModeError})
+	if err != nil {
+		// TapeSize is a fixed, positive constant above; newTape only ever
+		// errors on a non-positive TapeSize.
+		panic(err)
+	}
+	return &BrainfuckProgram{
+		tape:    tape,
+		eofMode: EOFUnchanged,
+		code:    bufio.NewReader(code),
+		in:      bufio.NewReader(in),
+		out:     bufio.NewWriter(out),
+	}
+}
+
+// Run executes a BrainfuckProgram created by NewStreamingBrainfuck to
+// completion, or until ctx is cancelled. It returns any read/write error
+// encountered along the way.
+//
+// Because a ']' may need to jump back before we've read forward past it, the
+// bytes of a loop body are buffered as they're first read, from its '[' to
+// its matching ']'; once the whole body is known it's folded and evaluated
+// like any other in-memory brainfuck source, so nested loops are handled the
+// same way recursively, one buffered body at a time.
+func (bf *BrainfuckProgram) Run(ctx context.Context) error {
+	defer bf.out.Flush()
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		b, err := bf.code.ReadByte()
+		if err == io.EOF {
+			bf.Finished = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch b {
+		case '>':
+			bf.DP++
+		case '<':
+			bf.DP--
+		case '+':
+			if err := bf.addCell(1); err != nil {
+				return err
+			}
+		case '-':
+			if err := bf.addCell(-1); err != nil {
+				return err
+			}
+		case '.':
+			if err := bf.writeCell(); err != nil {
+				return err
+			}
+		case ',':
+			if err := bf.readCell(); err != nil {
+				return err
+			}
+		case '[':
+			body, err := bf.captureLoopBody()
+			if err != nil {
+				return err
+			}
+			if err := bf.runLoopBody(ctx, body); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// captureLoopBody consumes bytes from bf.code, starting right after an
+// already-consumed '[', up to and including its matching ']', and returns
+// everything in between (nested brackets and all, but not the delimiters
+// themselves).
+func (bf *BrainfuckProgram) captureLoopBody() ([]byte, error) {
+	var body []byte
+	depth := 1
+	for {
+		b, err := bf.code.ReadByte()
+		if err == io.EOF {
+			return nil, fmt.Errorf("gobfk: unmatched '[' at EOF")
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return body, nil
+			}
+		}
+		body = append(body, b)
+	}
+}
+
+// runLoopBody folds a captured loop body into an Op stream once, then
+// re-executes it against the live tape for as long as the current cell is
+// non-zero on entry, exactly like a normal brainfuck loop.
+func (bf *BrainfuckProgram) runLoopBody(ctx context.Context, body []byte) error {
+	ops := fold(stringToTokens(string(body)))
+	matchBrackets(ops)
+
+	for {
+		v, err := bf.tape.Get(bf.DP)
+		if err != nil {
+			return err
+		}
+		if v == 0 {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		for pc := 0; pc < len(ops); pc++ {
+			op := ops[pc]
+			switch op.Kind {
+			case OpAdd:
+				if err := bf.addCell(int64(op.Arg)); err != nil {
+					return err
+				}
+			case OpMove:
+				bf.DP += op.Arg
+			case OpPrint:
+				if err := bf.writeCell(); err != nil {
+					return err
+				}
+			case OpRead:
+				if err := bf.readCell(); err != nil {
+					return err
+				}
+			case OpLoopL:
+				v, err := bf.tape.Get(bf.DP)
+				if err != nil {
+					return err
+				}
+				if v == 0 {
+					pc = op.Arg
+				}
+			case OpLoopR:
+				v, err := bf.tape.Get(bf.DP)
+				if err != nil {
+					return err
+				}
+				if v != 0 {
+					pc = op.Arg
+				}
+			case OpSetZero:
+				if err := bf.tape.Set(bf.DP, 0); err != nil {
+					return err
+				}
+			case OpMulMove:
+				offset, mult := unpackMulMove(op.Arg)
+				src, err := bf.tape.Get(bf.DP)
+				if err != nil {
+					return err
+				}
+				dst, err := bf.tape.Get(bf.DP + offset)
+				if err != nil {
+					return err
+				}
+				if err := bf.tape.Set(bf.DP+offset, dst+src*int64(mult)); err != nil {
+					return err
+				}
+			case OpScanRight:
+				idx, err := bf.tape.ScanZero(bf.DP, 1)
+				if err != nil {
+					return err
+				}
+				bf.DP = idx
+			case OpScanLeft:
+				idx, err := bf.tape.ScanZero(bf.DP, -1)
+				if err != nil {
+					return err
+				}
+				bf.DP = idx
+			}
+		}
+	}
+}
+
+// addCell adds delta to the current cell.
+func (bf *BrainfuckProgram) addCell(delta int64) error {
+	v, err := bf.tape.Get(bf.DP)
+	if err != nil {
+		return err
+	}
+	return bf.tape.Set(bf.DP, v+delta)
+}
+
+// writeCell writes the current cell to the output writer, flushing on
+// newline so line-buffered consumers see output promptly.
+func (bf *BrainfuckProgram) writeCell() error {
+	v, err := bf.tape.Get(bf.DP)
+	if err != nil {
+		return err
+	}
+	if err := bf.out.WriteByte(byte(v)); err != nil {
+		return err
+	}
+	if v == '\n' {
+		return bf.out.Flush()
+	}
+	return nil
+}
+
+// readCell reads one rune from the input reader into the current cell,
+// falling back to bf.eofMode's behavior on EOF.
+func (bf *BrainfuckProgram) readCell() error {
+	r, _, err := bf.in.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			return err
+		}
+		switch bf.eofMode {
+		case EOFMinusOne:
+			return bf.tape.Set(bf.DP, -1)
+		case EOFZero:
+			return bf.tape.Set(bf.DP, 0)
+		default:
+			return nil
+		}
+	}
+	return bf.tape.Set(bf.DP, int64(r))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestStreamingRunWritesOutput checks that Run executes a simple program
+// (including a loop) and writes its output to the given io.Writer.
+func TestStreamingRunWritesOutput(t *testing.T) {
+	var out bytes.Buffer
+	bf := NewStreamingBrainfuck(strings.NewReader("++++[>++++++++<-]>."), strings.NewReader(""), &out)
+	if err := bf.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.String() != "\x20" {
+		t.Errorf("output = %q, want %q", out.String(), "\x20")
+	}
+	if !bf.Finished {
+		t.Error("Finished = false after Run returned nil")
+	}
+}
+
+// TestStreamingRunReadsInput checks that Run reads from the given io.Reader
+// for ',' and reflects it back through '.'.
+func TestStreamingRunReadsInput(t *testing.T) {
+	var out bytes.Buffer
+	bf := NewStreamingBrainfuck(strings.NewReader(",."), strings.NewReader("A"), &out)
+	if err := bf.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.String() != "A" {
+		t.Errorf("output = %q, want %q", out.String(), "A")
+	}
+}
+
+// TestStreamingRunUnmatchedBracket checks that an unclosed '[' reports an
+// error instead of hanging, since captureLoopBody only finds the end of a
+// loop body by reading all the way to its matching ']'.
+func TestStreamingRunUnmatchedBracket(t *testing.T) {
+	bf := NewStreamingBrainfuck(strings.NewReader("[+"), strings.NewReader(""), &bytes.Buffer{})
+	if err := bf.Run(context.Background()); err == nil {
+		t.Error("Run with unmatched '[' = nil error, want an error")
+	}
+}
+
+// TestStreamingRunEOFUnchanged checks that NewStreamingBrainfuck leaves the
+// cell unchanged on EOF, matching its documented EOFUnchanged behavior.
+func TestStreamingRunEOFUnchanged(t *testing.T) {
+	var out bytes.Buffer
+	bf := NewStreamingBrainfuck(strings.NewReader("+,."), strings.NewReader(""), &out)
+	if err := bf.Run(context.Background()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out.String() != "\x01" {
+		t.Errorf("output = %q, want \\x01 (cell left unchanged by , on EOF)", out.String())
+	}
+}
+
+// TestStreamingRunContextCancelled checks that Run stops and returns ctx's
+// error once it's cancelled, rather than running to completion.
+func TestStreamingRunContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	bf := NewStreamingBrainfuck(strings.NewReader("+++++"), strings.NewReader(""), &bytes.Buffer{})
+	if err := bf.Run(ctx); err == nil {
+		t.Error("Run with a cancelled context = nil error, want ctx.Err()")
+	}
+}
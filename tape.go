@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// TapeMode selects how a Tape reacts to the data pointer moving out of its
+// current bounds.
+type TapeMode byte
+
+const (
+	// TapeModeError returns a *TapeError identifying the offending pointer
+	// instead of silently corrupting memory or panicking.
+	TapeModeError TapeMode = iota
+	// TapeModeWrap wraps the pointer around a fixed-size tape, as bfg does
+	// at 65535.
+	TapeModeWrap
+	// TapeModeGrow extends the tape to accommodate the new pointer. Only
+	// meaningful for dp >= 0; a negative dp is still an error under this
+	// mode, since there's nothing to grow backwards into.
+	TapeModeGrow
+)
+
+// CellWidth selects the integer width (and signedness) of each tape cell.
+type CellWidth byte
+
+const (
+	Cell8   CellWidth = iota // unsigned byte, the classic brainfuck cell
+	Cell16                   // unsigned 16-bit
+	Cell32                   // unsigned 32-bit
+	CellInt                  // platform-width signed int, matching bfg
+)
+
+// EOFMode selects what `,` stores in the current cell once the input
+// reader is exhausted.
+type EOFMode byte
+
+const (
+	EOFZero      EOFMode = iota // store 0 (the historical CreateBrainfuckProgram behavior)
+	EOFMinusOne                 // store -1
+	EOFUnchanged                // leave the cell as it was
+)
+
+// TapeError reports a data pointer that moved out of bounds on a Tape
+// running in TapeModeError.
+type TapeError struct {
+	DP int
+}
+
+func (e *TapeError) Error() string {
+	return fmt.Sprintf("gobfk: data pointer %d out of bounds", e.DP)
+}
+
+// Tape is the pluggable memory backing a BrainfuckProgram. Implementations
+// vary in cell width and signedness and in how they react to dp moving out
+// of bounds; Get/Set present every cell as an int64 so the rest of the
+// interpreter doesn't need to care which Tape is in play. Bounds are only
+// enforced (and, for TapeModeGrow, only grown) on access, not when dp itself
+// is moved.
+type Tape interface {
+	Get(dp int) (int64, error)
+	Set(dp int, v int64) error
+	// ScanZero returns the nearest dp' = dp, dp+step, dp+2*step, ... whose
+	// cell is zero, growing/wrapping/erroring along the way exactly as Get
+	// would at each step.
+	ScanZero(dp, step int) (int, error)
+}
+
+// scanZero is the shared, Get-based fallback for ScanZero: it simply walks
+// the tape one step at a time. Concrete Tapes may special-case a faster path
+// (see ByteTape) but fall back to this for anything it doesn't cover.
+func scanZero(t Tape, dp, step int) (int, error) {
+	for {
+		v, err := t.Get(dp)
+		if err != nil {
+			return 0, err
+		}
+		if v == 0 {
+			return dp, nil
+		}
+		dp += step
+	}
+}
+
+// resolve maps a requested index into cells' bounds according to mode,
+// growing or wrapping cells as needed, or reporting an error. It's shared by
+// every concrete Tape below.
+func resolve(size int, mode TapeMode, dp int) (int, error) {
+	if dp >= 0 && dp < size {
+		return dp, nil
+	}
+	switch mode {
+	case TapeModeWrap:
+		return ((dp % size) + size) % size, nil
+	case TapeModeGrow:
+		if dp < 0 {
+			return 0, &TapeError{DP: dp}
+		}
+		return dp, nil
+	default:
+		return 0, &TapeError{DP: dp}
+	}
+}
+
+// ByteTape is an 8-bit unsigned cell tape — the classic brainfuck cell, and
+// the same representation CreateBrainfuckProgram always used.
+type ByteTape struct {
+	cells []byte
+	mode  TapeMode
+}
+
+func newByteTape(size int, mode TapeMode) *ByteTape {
+	return &ByteTape{cells: make([]byte, size), mode: mode}
+}
+
+func (t *ByteTape) index(dp int) (int, error) {
+	i, err := resolve(len(t.cells), t.mode, dp)
+	if err != nil {
+		return 0, err
+	}
+	if i >= len(t.cells) { // TapeModeGrow
+		grown := make([]byte, i+1)
+		copy(grown, t.cells)
+		t.cells = grown
+	}
+	return i, nil
+}
+
+func (t *ByteTape) Get(dp int) (int64, error) {
+	i, err := t.index(dp)
+	if err != nil {
+		return 0, err
+	}
+	return int64(t.cells[i]), nil
+}
+
+func (t *ByteTape) Set(dp int, v int64) error {
+	i, err := t.index(dp)
+	if err != nil {
+		return err
+	}
+	t.cells[i] = byte(v)
+	return nil
+}
+
+func (t *ByteTape) ScanZero(dp, step int) (int, error) {
+	if step == 1 {
+		if i, err := t.index(dp); err == nil {
+			if idx := bytes.IndexByte(t.cells[i:], 0); idx >= 0 {
+				return i + idx, nil
+			}
+		}
+	}
+	return scanZero(t, dp, step)
+}
+
+// Uint16Tape is a 16-bit unsigned cell tape.
+type Uint16Tape struct {
+	cells []uint16
+	mode  TapeMode
+}
+
+func newUint16Tape(size int, mode TapeMode) *Uint16Tape {
+	return &Uint16Tape{cells: make([]uint16, size), mode: mode}
+}
+
+func (t *Uint16Tape) index(dp int) (int, error) {
+	i, err := resolve(len(t.cells), t.mode, dp)
+	if err != nil {
+		return 0, err
+	}
+	if i >= len(t.cells) {
+		grown := make([]uint16, i+1)
+		copy(grown, t.cells)
+		t.cells = grown
+	}
+	return i, nil
+}
+
+func (t *Uint16Tape) Get(dp int) (int64, error) {
+	i, err := t.index(dp)
+	if err != nil {
+		return 0, err
+	}
+	return int64(t.cells[i]), nil
+}
+
+func (t *Uint16Tape) Set(dp int, v int64) error {
+	i, err := t.index(dp)
+	if err != nil {
+		return err
+	}
+	t.cells[i] = uint16(v)
+	return nil
+}
+
+func (t *Uint16Tape) ScanZero(dp, step int) (int, error) {
+	return scanZero(t, dp, step)
+}
+
+// Uint32Tape is a 32-bit unsigned cell tape.
+type Uint32Tape struct {
+	cells []uint32
+	mode  TapeMode
+}
+
+func newUint32Tape(size int, mode TapeMode) *Uint32Tape {
+	return &Uint32Tape{cells: make([]uint32, size), mode: mode}
+}
+
+func (t *Uint32Tape) index(dp int) (int, error) {
+	i, err := resolve(len(t.cells), t.mode, dp)
+	if err != nil {
+		return 0, err
+	}
+	if i >= len(t.cells) {
+		grown := make([]uint32, i+1)
+		copy(grown, t.cells)
+		t.cells = grown
+	}
+	return i, nil
+}
+
+func (t *Uint32Tape) Get(dp int) (int64, error) {
+	i, err := t.index(dp)
+	if err != nil {
+		return 0, err
+	}
+	return int64(t.cells[i]), nil
+}
+
+func (t *Uint32Tape) Set(dp int, v int64) error {
+	i, err := t.index(dp)
+	if err != nil {
+		return err
+	}
+	t.cells[i] = uint32(v)
+	return nil
+}
+
+func (t *Uint32Tape) ScanZero(dp, step int) (int, error) {
+	return scanZero(t, dp, step)
+}
+
+// IntTape is a platform-width signed cell tape, matching bfg's choice of
+// signed int cells.
+type IntTape struct {
+	cells []int
+	mode  TapeMode
+}
+
+func newIntTape(size int, mode TapeMode) *IntTape {
+	return &IntTape{cells: make([]int, size), mode: mode}
+}
+
+func (t *IntTape) index(dp int) (int, error) {
+	i, err := resolve(len(t.cells), t.mode, dp)
+	if err != nil {
+		return 0, err
+	}
+	if i >= len(t.cells) {
+		grown := make([]int, i+1)
+		copy(grown, t.cells)
+		t.cells = grown
+	}
+	return i, nil
+}
+
+func (t *IntTape) Get(dp int) (int64, error) {
+	i, err := t.index(dp)
+	if err != nil {
+		return 0, err
+	}
+	return int64(t.cells[i]), nil
+}
+
+func (t *IntTape) Set(dp int, v int64) error {
+	i, err := t.index(dp)
+	if err != nil {
+		return err
+	}
+	t.cells[i] = int(v)
+	return nil
+}
+
+func (t *IntTape) ScanZero(dp, step int) (int, error) {
+	return scanZero(t, dp, step)
+}
+
+// newTape builds the Tape implementation selected by opts. It rejects a
+// non-positive TapeSize instead of letting resolve divide by zero under
+// TapeModeWrap (or build a useless empty tape under any other mode).
+func newTape(opts Options) (Tape, error) {
+	if opts.TapeSize <= 0 {
+		return nil, fmt.Errorf("gobfk: tape size must be positive, got %d", opts.TapeSize)
+	}
+	switch opts.CellWidth {
+	case Cell16:
+		return newUint16Tape(opts.TapeSize, opts.TapeMode), nil
+	case Cell32:
+		return newUint32Tape(opts.TapeSize, opts.TapeMode), nil
+	case CellInt:
+		return newIntTape(opts.TapeSize, opts.TapeMode), nil
+	default:
+		return newByteTape(opts.TapeSize, opts.TapeMode), nil
+	}
+}
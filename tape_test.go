@@ -0,0 +1,177 @@
+package main
+
+import "testing"
+
+// TestByteTapeGetSet checks basic Get/Set round-tripping on a ByteTape.
+func TestByteTapeGetSet(t *testing.T) {
+	tape := newByteTape(4, TapeModeError)
+	if err := tape.Set(2, 65); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	v, err := tape.Get(2)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if v != 65 {
+		t.Errorf("Get(2) = %d, want 65", v)
+	}
+}
+
+// TestTapeModeError checks that an out-of-bounds dp reports a *TapeError
+// instead of panicking or silently wrapping.
+func TestTapeModeError(t *testing.T) {
+	tape := newByteTape(4, TapeModeError)
+	if _, err := tape.Get(-1); err == nil {
+		t.Fatal("Get(-1) = nil error, want a *TapeError")
+	}
+	if _, err := tape.Get(4); err == nil {
+		t.Fatal("Get(4) = nil error, want a *TapeError")
+	}
+}
+
+// TestTapeModeWrap checks that an out-of-bounds dp wraps around the tape in
+// both directions.
+func TestTapeModeWrap(t *testing.T) {
+	tape := newByteTape(4, TapeModeWrap)
+	if err := tape.Set(0, 9); err != nil {
+		t.Fatalf("Set(0): %v", err)
+	}
+	v, err := tape.Get(4)
+	if err != nil {
+		t.Fatalf("Get(4): %v", err)
+	}
+	if v != 9 {
+		t.Errorf("Get(4) = %d, want 9 (wrapped to index 0)", v)
+	}
+	if _, err := tape.Get(-1); err != nil {
+		t.Errorf("Get(-1) under TapeModeWrap: %v, want no error", err)
+	}
+}
+
+// TestTapeModeGrow checks that a positive out-of-bounds dp grows the tape,
+// while a negative one still errors (there's nothing to grow backwards into).
+func TestTapeModeGrow(t *testing.T) {
+	tape := newByteTape(2, TapeModeGrow)
+	if err := tape.Set(10, 7); err != nil {
+		t.Fatalf("Set(10): %v", err)
+	}
+	v, err := tape.Get(10)
+	if err != nil {
+		t.Fatalf("Get(10): %v", err)
+	}
+	if v != 7 {
+		t.Errorf("Get(10) = %d, want 7", v)
+	}
+	if _, err := tape.Get(-1); err == nil {
+		t.Error("Get(-1) under TapeModeGrow = nil error, want a *TapeError")
+	}
+}
+
+// TestByteTapeScanZero checks the bytes.IndexByte fast path against the
+// shared scanZero fallback by comparing step 1 and step -1 results.
+func TestByteTapeScanZero(t *testing.T) {
+	tape := newByteTape(8, TapeModeError)
+	for _, dp := range []int{1, 2, 3} {
+		if err := tape.Set(dp, 1); err != nil {
+			t.Fatalf("Set(%d): %v", dp, err)
+		}
+	}
+	idx, err := tape.ScanZero(1, 1)
+	if err != nil {
+		t.Fatalf("ScanZero(1, 1): %v", err)
+	}
+	if idx != 4 {
+		t.Errorf("ScanZero(1, 1) = %d, want 4", idx)
+	}
+}
+
+// TestNewTapeRejectsNonPositiveSize checks that newTape errors instead of
+// building a Tape that would divide by zero under TapeModeWrap.
+func TestNewTapeRejectsNonPositiveSize(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		if _, err := newTape(Options{CellWidth: Cell8, TapeSize: size, TapeMode: TapeModeWrap}); err == nil {
+			t.Errorf("newTape(TapeSize: %d) = nil error, want an error", size)
+		}
+	}
+}
+
+// TestWideTapesGetSetAndBounds checks Get/Set round-tripping and
+// TapeModeError bounds checking on the three non-ByteTape implementations,
+// which ByteTape-only tests never exercised.
+func TestWideTapesGetSetAndBounds(t *testing.T) {
+	cases := []struct {
+		name string
+		tape Tape
+	}{
+		{"Uint16Tape", newUint16Tape(4, TapeModeError)},
+		{"Uint32Tape", newUint32Tape(4, TapeModeError)},
+		{"IntTape", newIntTape(4, TapeModeError)},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.tape.Set(1, 1000); err != nil {
+				t.Fatalf("Set: %v", err)
+			}
+			v, err := c.tape.Get(1)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if v != 1000 {
+				t.Errorf("Get(1) = %d, want 1000", v)
+			}
+			if _, err := c.tape.Get(4); err == nil {
+				t.Error("Get(4) = nil error, want a *TapeError")
+			}
+		})
+	}
+}
+
+// TestUint16TapeTruncates checks that a value wider than 16 bits truncates
+// on Set, the same way ByteTape truncates to 8 bits.
+func TestUint16TapeTruncates(t *testing.T) {
+	tape := newUint16Tape(1, TapeModeError)
+	if err := tape.Set(0, 1<<16+5); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	v, err := tape.Get(0)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if v != 5 {
+		t.Errorf("Get(0) = %d, want 5 (truncated to uint16)", v)
+	}
+}
+
+// TestIntTapeSignedValues checks that IntTape, unlike the unsigned tapes,
+// stores negative cell values without wrapping.
+func TestIntTapeSignedValues(t *testing.T) {
+	tape := newIntTape(2, TapeModeError)
+	if err := tape.Set(0, -1); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	v, err := tape.Get(0)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if v != -1 {
+		t.Errorf("Get(0) = %d, want -1", v)
+	}
+}
+
+// TestUint32TapeScanZero checks that the shared scanZero fallback (not
+// ByteTape's bytes.IndexByte fast path) finds a zero cell correctly.
+func TestUint32TapeScanZero(t *testing.T) {
+	tape := newUint32Tape(4, TapeModeError)
+	for _, dp := range []int{0, 1} {
+		if err := tape.Set(dp, 1); err != nil {
+			t.Fatalf("Set(%d): %v", dp, err)
+		}
+	}
+	idx, err := tape.ScanZero(0, 1)
+	if err != nil {
+		t.Fatalf("ScanZero(0, 1): %v", err)
+	}
+	if idx != 2 {
+		t.Errorf("ScanZero(0, 1) = %d, want 2", idx)
+	}
+}